@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/Danlock/dir-treeify/internal/fs"
+	"github.com/Danlock/dir-treeify/internal/ignore"
+)
+
+// treeifyIgnoreFile is the name of the optional file, rooted in inputDir,
+// holding additional ignore patterns in .gitignore-like syntax.
+const treeifyIgnoreFile = ".treeifyignore"
+
+// realPath turns name, relative to fsys's root, into the path that should be
+// passed to Symlink as its target. For a BasicFilesystem this is the real
+// path on disk; for other backends it's just fsys's root joined with name.
+func realPath(fsys fs.Filesystem, name string) string {
+	return filepath.Join(fs.RootPath(fsys), name)
+}
+
+// isOutputDir reports whether name (relative to inFs's root) is the
+// directory outFs is rooted at, so the walk can avoid descending into its
+// own output and looping on the symlinks it just created.
+func isOutputDir(inFs, outFs fs.Filesystem, name string) bool {
+	return realPath(inFs, name) == fs.RootPath(outFs)
+}
+
+// loadPathIgnore reads .treeifyignore from inFs's root, if present. Only the
+// basic (on-disk) backend can hold such a file; other backends get an empty
+// matcher.
+func loadPathIgnore(inFs fs.Filesystem) (*ignore.Matcher, error) {
+	if inFs.Type() != fs.FilesystemTypeBasic {
+		return ignore.New(), nil
+	}
+	f, err := os.Open(filepath.Join(fs.RootPath(inFs), treeifyIgnoreFile))
+	if os.IsNotExist(err) {
+		return ignore.New(), nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	patterns, err := ignore.ReadPatterns(f)
+	if err != nil {
+		return nil, err
+	}
+	return ignore.New(patterns...), nil
+}
+
+// findMatches walks inFs looking for directories whose name matches
+// flags.Regex, pruning each match's subtree so that nested hierarchies are
+// flattened rather than linked at every level.
+func findMatches(flags Flags, inFs, outFs fs.Filesystem) ([]string, error) {
+	var matches []string
+	opts := fs.WalkOptions{MaxDepth: flags.MaxDepth, FollowSymlinks: flags.FollowSymlinks}
+	err := fs.Walk(inFs, ".", opts, func(name string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if name == "." {
+			return nil
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if isOutputDir(inFs, outFs, name) {
+			return fs.SkipDir
+		}
+		if flags.PathIgnore.ShouldIgnore(name) {
+			return fs.SkipDir
+		}
+
+		fName := strings.TrimSpace(info.Name())
+		if !strings.ContainsAny(fName, "[ & ]") {
+			// Doesn't look like a match at this level; keep descending in
+			// case a nested folder further down does.
+			return nil
+		}
+		if flags.Regex.FindStringSubmatch(fName) == nil {
+			log.Printf("Could not parse folder %s, descending into it", fName)
+			return nil
+		}
+		matches = append(matches, name)
+		return fs.SkipDir
+	})
+	return matches, err
+}
+
+// parentChildNames extracts the parent/child capture groups flags.Regex
+// produced from fName, the base name of a matched directory.
+func parentChildNames(flags Flags, fName string) (parentName, childName string, ok bool) {
+	folderNames := flags.Regex.FindStringSubmatch(fName)
+	if folderNames == nil {
+		return "", "", false
+	}
+	for i, v := range flags.Regex.SubexpNames() {
+		if v == "parent" {
+			parentName = strings.ToLower(folderNames[i])
+		} else if v == "child" {
+			childName = strings.TrimSpace(folderNames[i])
+		}
+	}
+	return parentName, childName, true
+}
+
+// processEntry links name, a directory relative to inFs that's already
+// matched flags.Regex, into its parent/child destination under outFs using
+// flags.LinkMode. It's
+// shared by the one-shot consolidation pass and watch mode's per-event
+// handling. ignored reports whether name was skipped because of
+// IgnoreParents/IgnoreChildren, as opposed to failing outright. dest is
+// returned even when err != nil, for reporting purposes, whenever it could
+// be computed.
+func processEntry(flags Flags, inFs, outFs fs.Filesystem, name string) (dest string, ignored bool, err error) {
+	parentName, childName, ok := parentChildNames(flags, filepath.Base(name))
+	if !ok {
+		return "", false, fmt.Errorf("could not parse folder %s", name)
+	}
+	dest = filepath.Join(parentName, childName)
+
+	if flags.IgnoreParents.ShouldIgnore(parentName) || flags.IgnoreChildren.ShouldIgnore(childName) {
+		return dest, true, nil
+	}
+
+	if !outFs.SupportsLinkMode(flags.LinkMode) {
+		return dest, false, fmt.Errorf("%s does not support --link-mode %s", outFs.URI(), flags.LinkMode)
+	}
+
+	info, err := inFs.Lstat(name)
+	if err != nil {
+		return dest, false, fmt.Errorf("could not stat folder %s: %w", name, err)
+	}
+
+	src := realPath(inFs, name)
+	destParent := filepath.Join(parentName)
+
+	if err := outFs.MkdirAll(destParent, info.Mode()); err != nil {
+		return dest, false, fmt.Errorf("could not create destination folder %s: %w", destParent, err)
+	}
+
+	if _, err := outFs.Lstat(dest); err == nil {
+		// Already consolidated from a previous run; replace it whether it's
+		// a symlink, or a hardlinked/copied/reflinked tree.
+		if err := outFs.RemoveAll(dest); err != nil {
+			return dest, false, fmt.Errorf("failed to remove existing destination %s: %w", dest, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return dest, false, fmt.Errorf("destination not created: %w", err)
+	}
+
+	if err := outFs.Link(src, dest, flags.LinkMode); err != nil {
+		return dest, false, fmt.Errorf("error linking dir %s to %s: %w", src, dest, err)
+	}
+	return dest, false, nil
+}
+
+// removeEntry removes name's destination (a symlink, or a hardlinked,
+// copied or reflinked tree) under outFs, if any. It's used by watch mode
+// when a previously matched directory disappears from inFs.
+func removeEntry(flags Flags, outFs fs.Filesystem, name string) error {
+	parentName, childName, ok := parentChildNames(flags, filepath.Base(name))
+	if !ok {
+		return nil
+	}
+	dest := filepath.Join(parentName, childName)
+	if err := outFs.RemoveAll(dest); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not remove destination %s: %w", dest, err)
+	}
+	return nil
+}
+
+// consolidateFolders links every folder in inFs matching flags.Regex into
+// outFs, processing up to flags.Concurrency entries at once. It returns one
+// Result per match; a per-entry error is recorded on its Result rather than
+// aborting the rest of the batch, so group.Go's func always returns nil.
+func consolidateFolders(flags Flags, inFs, outFs fs.Filesystem) ([]Result, error) {
+	matches, err := findMatches(flags, inFs, outFs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(matches))
+	var group errgroup.Group
+	group.SetLimit(flags.Concurrency)
+	for i, name := range matches {
+		i, name := i, name
+		group.Go(func() error {
+			dest, ignored, err := processEntry(flags, inFs, outFs, name)
+			status := "linked"
+			switch {
+			case ignored:
+				status = "ignored"
+			case err != nil:
+				status = "error"
+			}
+			results[i] = Result{Src: realPath(inFs, name), Dest: dest, Status: status, Err: err}
+			return nil
+		})
+	}
+	group.Wait()
+	return results, nil
+}
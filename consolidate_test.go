@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/Danlock/dir-treeify/internal/fs"
+	"github.com/Danlock/dir-treeify/internal/ignore"
+)
+
+func testFlags() Flags {
+	return Flags{
+		Regex:          regexp.MustCompile(`\[(?P<parent>.+?)\](?P<child>.+)`),
+		IgnoreParents:  ignore.New(),
+		IgnoreChildren: ignore.New(),
+		LinkMode:       fs.LinkModeSymlink,
+	}
+}
+
+func TestParentChildNames(t *testing.T) {
+	flags := testFlags()
+
+	parent, child, ok := parentChildNames(flags, "[Big Shaq] Mans not Hot")
+	if !ok {
+		t.Fatal("expected a match")
+	}
+	if parent != "big shaq" {
+		t.Errorf("parent = %q, want %q", parent, "big shaq")
+	}
+	if child != "Mans not Hot" {
+		t.Errorf("child = %q, want %q", child, "Mans not Hot")
+	}
+
+	if _, _, ok := parentChildNames(flags, "no brackets here"); ok {
+		t.Error("expected no match for a name without brackets")
+	}
+}
+
+func TestProcessEntryLinksMatch(t *testing.T) {
+	flags := testFlags()
+	inFs := fs.NewMemFilesystem("in")
+	outFs := fs.NewMemFilesystem("out")
+
+	name := "[Big Shaq] Mans not Hot"
+	if err := inFs.MkdirAll(name, 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+
+	dest, ignored, err := processEntry(flags, inFs, outFs, name)
+	if err != nil {
+		t.Fatalf("processEntry: %v", err)
+	}
+	if ignored {
+		t.Fatal("expected entry not to be ignored")
+	}
+	if want := "big shaq/Mans not Hot"; dest != want {
+		t.Errorf("dest = %q, want %q", dest, want)
+	}
+
+	info, err := outFs.Lstat(dest)
+	if err != nil {
+		t.Fatalf("Lstat(%q): %v", dest, err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("expected %q to be a symlink, got mode %v", dest, info.Mode())
+	}
+}
+
+func TestProcessEntryRespectsIgnoreParents(t *testing.T) {
+	flags := testFlags()
+	flags.IgnoreParents = ignore.New("big shaq")
+	inFs := fs.NewMemFilesystem("in")
+	outFs := fs.NewMemFilesystem("out")
+
+	name := "[Big Shaq] Mans not Hot"
+	if err := inFs.MkdirAll(name, 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+
+	dest, ignored, err := processEntry(flags, inFs, outFs, name)
+	if err != nil {
+		t.Fatalf("processEntry: %v", err)
+	}
+	if !ignored {
+		t.Fatal("expected entry to be ignored")
+	}
+	if _, err := outFs.Lstat(dest); err == nil {
+		t.Errorf("expected nothing to be created at %q", dest)
+	}
+}
+
+func TestProcessEntryRejectsUnsupportedLinkMode(t *testing.T) {
+	flags := testFlags()
+	flags.LinkMode = fs.LinkModeHardlink
+	inFs := fs.NewMemFilesystem("in")
+	outFs := fs.NewMemFilesystem("out")
+
+	name := "[Big Shaq] Mans not Hot"
+	if err := inFs.MkdirAll(name, 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+
+	if _, _, err := processEntry(flags, inFs, outFs, name); err == nil {
+		t.Error("expected an error since MemFilesystem only supports symlink")
+	}
+}
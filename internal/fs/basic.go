@@ -0,0 +1,97 @@
+package fs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// BasicFilesystem implements Filesystem on top of the OS's own filesystem,
+// rooted at a single directory.
+type BasicFilesystem struct {
+	root string
+}
+
+// NewBasicFilesystem returns a Filesystem rooted at root, a path on local
+// disk. root is canonicalized to an absolute path so that symlink targets
+// built from it (see realPath in consolidate.go) resolve correctly
+// regardless of the symlink's own location, not just the process's cwd.
+func NewBasicFilesystem(root string) *BasicFilesystem {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		abs = filepath.Clean(root)
+	}
+	return &BasicFilesystem{root: abs}
+}
+
+func (f *BasicFilesystem) Type() FilesystemType { return FilesystemTypeBasic }
+
+func (f *BasicFilesystem) URI() string { return "file://" + f.root }
+
+func (f *BasicFilesystem) resolve(name string) string {
+	return filepath.Join(f.root, name)
+}
+
+func (f *BasicFilesystem) ReadDir(name string) ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(f.resolve(name))
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (f *BasicFilesystem) Lstat(name string) (os.FileInfo, error) {
+	return os.Lstat(f.resolve(name))
+}
+
+func (f *BasicFilesystem) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(f.resolve(name))
+}
+
+func (f *BasicFilesystem) MkdirAll(name string, perm os.FileMode) error {
+	return os.MkdirAll(f.resolve(name), perm)
+}
+
+// SupportsLinkMode reports true for every mode: hardlink/reflink can still
+// fail at call time (e.g. across devices, or without kernel CoW support),
+// but a BasicFilesystem can always attempt them.
+func (f *BasicFilesystem) SupportsLinkMode(mode LinkMode) bool {
+	switch mode {
+	case LinkModeSymlink, LinkModeHardlink, LinkModeReflink, LinkModeCopy:
+		return true
+	default:
+		return false
+	}
+}
+
+func (f *BasicFilesystem) Link(oldname, newname string, mode LinkMode) error {
+	dest := f.resolve(newname)
+	switch mode {
+	case LinkModeSymlink, "":
+		return os.Symlink(oldname, dest)
+	case LinkModeHardlink:
+		return hardlinkTree(oldname, dest)
+	case LinkModeCopy:
+		return copyTree(oldname, dest)
+	case LinkModeReflink:
+		return reflinkTree(oldname, dest)
+	default:
+		return fmt.Errorf("fs: unsupported link mode %q", mode)
+	}
+}
+
+func (f *BasicFilesystem) Remove(name string) error {
+	return os.Remove(f.resolve(name))
+}
+
+func (f *BasicFilesystem) RemoveAll(name string) error {
+	return os.RemoveAll(f.resolve(name))
+}
@@ -0,0 +1,90 @@
+// Package fs abstracts the filesystem operations dir-treeify needs, so the
+// folder-consolidation logic can target backends other than the local disk
+// (and be exercised in tests without touching one).
+package fs
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FilesystemType identifies which Filesystem implementation backs a URI.
+type FilesystemType string
+
+const (
+	FilesystemTypeBasic  FilesystemType = "basic"
+	FilesystemTypeMemory FilesystemType = "memory"
+)
+
+// LinkMode selects how a matched directory gets materialized under the
+// destination Filesystem.
+type LinkMode string
+
+const (
+	LinkModeSymlink  LinkMode = "symlink"
+	LinkModeHardlink LinkMode = "hardlink"
+	LinkModeReflink  LinkMode = "reflink"
+	LinkModeCopy     LinkMode = "copy"
+)
+
+// Filesystem is the set of operations consolidateFolders needs against a
+// directory tree. Implementations are rooted at the directory passed to New,
+// and every name they're given is relative to that root.
+type Filesystem interface {
+	// Type reports which backend implements this Filesystem.
+	Type() FilesystemType
+	// URI returns this Filesystem's root as a URI, scheme included. The
+	// basic backend reports its root made absolute.
+	URI() string
+
+	ReadDir(name string) ([]os.FileInfo, error)
+	Lstat(name string) (os.FileInfo, error)
+	// Stat is like Lstat but follows a trailing symlink.
+	Stat(name string) (os.FileInfo, error)
+	MkdirAll(name string, perm os.FileMode) error
+	// SupportsLinkMode reports whether this Filesystem can carry out Link
+	// with mode, so callers can fail with a clear error instead of
+	// discovering it mid-operation.
+	SupportsLinkMode(mode LinkMode) bool
+	// Link materializes oldname, an absolute source path, at newname
+	// (relative to this Filesystem's root) using mode. For LinkModeSymlink
+	// this is a plain symlink; the other modes recreate oldname's tree at
+	// newname via hardlinks, a CoW clone, or a streamed copy.
+	Link(oldname, newname string, mode LinkMode) error
+	Remove(name string) error
+	// RemoveAll removes name and everything under it, relative to this
+	// Filesystem's root.
+	RemoveAll(name string) error
+}
+
+// New builds a Filesystem rooted at uri. uri is either a bare path, which is
+// treated as a "file://" path, or a scheme-prefixed URI such as "mem://tmp".
+func New(uri string) (Filesystem, error) {
+	scheme, root := splitURI(uri)
+	switch scheme {
+	case "", "file":
+		return NewBasicFilesystem(root), nil
+	case "mem":
+		return NewMemFilesystem(root), nil
+	default:
+		return nil, fmt.Errorf("fs: unsupported scheme %q in uri %q", scheme, uri)
+	}
+}
+
+// splitURI splits uri into its scheme and the remainder. A uri with no
+// "scheme://" prefix is returned with an empty scheme and is left untouched.
+func splitURI(uri string) (scheme, rest string) {
+	idx := strings.Index(uri, "://")
+	if idx == -1 {
+		return "", uri
+	}
+	return uri[:idx], uri[idx+len("://"):]
+}
+
+// RootPath returns fsys's root, with its URI scheme stripped, so callers can
+// join it with a relative name to get a path meaningful to that backend.
+func RootPath(fsys Filesystem) string {
+	_, root := splitURI(fsys.URI())
+	return root
+}
@@ -0,0 +1,104 @@
+package fs
+
+import (
+	"io"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+)
+
+// hardlinkTree recreates src's tree at dst, hardlinking every regular file
+// with os.Link and recreating directories with their original mode.
+func hardlinkTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target, err := treeTarget(src, dst, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			return os.MkdirAll(target, info.Mode())
+		}
+		return os.Link(path, target)
+	})
+}
+
+// copyTree recreates src's tree at dst, streaming every regular file's
+// contents through io.Copy and preserving its mode bits.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target, err := treeTarget(src, dst, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(target, info.Mode())
+		}
+		return copyFile(path, target, info.Mode())
+	})
+}
+
+// reflinkTree recreates src's tree at dst like copyTree, but clones each
+// regular file with a copy-on-write reflink where the platform supports it,
+// falling back to a streamed copy otherwise.
+func reflinkTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target, err := treeTarget(src, dst, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(target, info.Mode())
+		}
+		if err := reflinkFile(path, target, info.Mode()); err != nil {
+			return copyFile(path, target, info.Mode())
+		}
+		return nil
+	})
+}
+
+func treeTarget(src, dst, path string) (string, error) {
+	rel, err := filepath.Rel(src, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dst, rel), nil
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
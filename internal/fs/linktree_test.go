@@ -0,0 +1,141 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// buildSrcTree creates src/a/b/file.txt under a fresh temp dir and returns
+// the src directory's path.
+func buildSrcTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	if err := os.MkdirAll(filepath.Join(src, "a", "b"), 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "a", "b", "file.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return src
+}
+
+func TestHardlinkTree(t *testing.T) {
+	src := buildSrcTree(t)
+	dst := filepath.Join(filepath.Dir(src), "dst")
+
+	if err := hardlinkTree(src, dst); err != nil {
+		t.Fatalf("hardlinkTree: %v", err)
+	}
+
+	srcFile := filepath.Join(src, "a", "b", "file.txt")
+	dstFile := filepath.Join(dst, "a", "b", "file.txt")
+
+	got, err := os.ReadFile(dstFile)
+	if err != nil {
+		t.Fatalf("ReadFile(%q): %v", dstFile, err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("content = %q, want %q", got, "hello")
+	}
+
+	srcInfo, err := os.Stat(srcFile)
+	if err != nil {
+		t.Fatalf("Stat(%q): %v", srcFile, err)
+	}
+	dstInfo, err := os.Stat(dstFile)
+	if err != nil {
+		t.Fatalf("Stat(%q): %v", dstFile, err)
+	}
+	if !os.SameFile(srcInfo, dstInfo) {
+		t.Error("expected hardlinkTree to share an inode between src and dst")
+	}
+}
+
+func TestCopyTree(t *testing.T) {
+	src := buildSrcTree(t)
+	dst := filepath.Join(filepath.Dir(src), "dst")
+
+	if err := copyTree(src, dst); err != nil {
+		t.Fatalf("copyTree: %v", err)
+	}
+
+	srcFile := filepath.Join(src, "a", "b", "file.txt")
+	dstFile := filepath.Join(dst, "a", "b", "file.txt")
+
+	got, err := os.ReadFile(dstFile)
+	if err != nil {
+		t.Fatalf("ReadFile(%q): %v", dstFile, err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("content = %q, want %q", got, "hello")
+	}
+
+	srcInfo, err := os.Stat(srcFile)
+	if err != nil {
+		t.Fatalf("Stat(%q): %v", srcFile, err)
+	}
+	dstInfo, err := os.Stat(dstFile)
+	if err != nil {
+		t.Fatalf("Stat(%q): %v", dstFile, err)
+	}
+	if os.SameFile(srcInfo, dstInfo) {
+		t.Error("expected copyTree to produce an independent file, not share an inode")
+	}
+
+	if err := os.WriteFile(dstFile, []byte("changed"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	srcAfter, err := os.ReadFile(srcFile)
+	if err != nil {
+		t.Fatalf("ReadFile(%q): %v", srcFile, err)
+	}
+	if string(srcAfter) != "hello" {
+		t.Error("expected editing dst after copyTree to leave src untouched")
+	}
+}
+
+// TestReflinkTreeFallsBackToCopy exercises reflinkTree on a tmp filesystem
+// without asserting CoW sharing: t.TempDir() isn't guaranteed to support
+// FICLONE (and never does on non-Linux), so this only pins the contract
+// that content always ends up correct, whether by a clone or a copy
+// fallback.
+func TestReflinkTreeFallsBackToCopy(t *testing.T) {
+	src := buildSrcTree(t)
+	dst := filepath.Join(filepath.Dir(src), "dst")
+
+	if err := reflinkTree(src, dst); err != nil {
+		t.Fatalf("reflinkTree: %v", err)
+	}
+
+	dstFile := filepath.Join(dst, "a", "b", "file.txt")
+	got, err := os.ReadFile(dstFile)
+	if err != nil {
+		t.Fatalf("ReadFile(%q): %v", dstFile, err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("content = %q, want %q", got, "hello")
+	}
+}
+
+func TestCopyFilePreservesMode(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src.txt")
+	dst := filepath.Join(root, "dst.txt")
+	if err := os.WriteFile(src, []byte("hello"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if err := copyFile(src, dst, 0600); err != nil {
+		t.Fatalf("copyFile: %v", err)
+	}
+
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatalf("Stat(%q): %v", dst, err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want %v", info.Mode().Perm(), os.FileMode(0600))
+	}
+}
@@ -0,0 +1,175 @@
+package fs
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemFilesystem is an in-memory Filesystem, useful for exercising the
+// folder-consolidation logic in tests without touching real disk.
+type MemFilesystem struct {
+	root string
+
+	mu      sync.Mutex
+	entries map[string]*memEntry
+}
+
+type memEntry struct {
+	isDir         bool
+	mode          os.FileMode
+	symlinkTarget string
+}
+
+// NewMemFilesystem returns an empty in-memory Filesystem. root is cosmetic;
+// it only affects the value URI() returns.
+func NewMemFilesystem(root string) *MemFilesystem {
+	return &MemFilesystem{
+		root:    root,
+		entries: map[string]*memEntry{},
+	}
+}
+
+func (f *MemFilesystem) Type() FilesystemType { return FilesystemTypeMemory }
+
+func (f *MemFilesystem) URI() string { return "mem://" + f.root }
+
+func clean(name string) string {
+	return path.Clean(name)
+}
+
+func (f *MemFilesystem) ReadDir(name string) ([]os.FileInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	dir := clean(name)
+	if dir != "." {
+		entry, ok := f.entries[dir]
+		if !ok || !entry.isDir {
+			return nil, &os.PathError{Op: "readdir", Path: name, Err: os.ErrNotExist}
+		}
+	}
+
+	var infos []os.FileInfo
+	for childPath, entry := range f.entries {
+		if path.Dir(childPath) != dir || childPath == dir {
+			continue
+		}
+		infos = append(infos, memFileInfo{name: path.Base(childPath), entry: entry})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	return infos, nil
+}
+
+func (f *MemFilesystem) Lstat(name string) (os.FileInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := clean(name)
+	if key == "." {
+		return memFileInfo{name: ".", entry: &memEntry{isDir: true, mode: os.ModeDir | 0755}}, nil
+	}
+	entry, ok := f.entries[key]
+	if !ok {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: os.ErrNotExist}
+	}
+	return memFileInfo{name: path.Base(key), entry: entry}, nil
+}
+
+// Stat is like Lstat, except a symlink whose target lies within this same
+// Filesystem is resolved to the entry it points at. A symlink pointing
+// outside this Filesystem's root is returned as-is, since there's nothing
+// here to resolve it against.
+func (f *MemFilesystem) Stat(name string) (os.FileInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := clean(name)
+	entry, ok := f.entries[key]
+	if !ok {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	if entry.mode&os.ModeSymlink != 0 {
+		if rel := strings.TrimPrefix(entry.symlinkTarget, f.root+"/"); rel != entry.symlinkTarget {
+			if target, ok := f.entries[clean(rel)]; ok {
+				return memFileInfo{name: path.Base(clean(rel)), entry: target}, nil
+			}
+		}
+	}
+	return memFileInfo{name: path.Base(key), entry: entry}, nil
+}
+
+func (f *MemFilesystem) MkdirAll(name string, perm os.FileMode) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := clean(name)
+	for key != "." {
+		if _, ok := f.entries[key]; !ok {
+			f.entries[key] = &memEntry{isDir: true, mode: perm | os.ModeDir}
+		}
+		key = path.Dir(key)
+	}
+	return nil
+}
+
+// SupportsLinkMode is true only for LinkModeSymlink: a MemFilesystem's
+// entries don't hold real file content, so there's nothing for hardlink,
+// reflink or copy to operate on.
+func (f *MemFilesystem) SupportsLinkMode(mode LinkMode) bool {
+	return mode == LinkModeSymlink || mode == ""
+}
+
+func (f *MemFilesystem) Link(oldname, newname string, mode LinkMode) error {
+	if !f.SupportsLinkMode(mode) {
+		return fmt.Errorf("fs: mem filesystem only supports %s, not %s", LinkModeSymlink, mode)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.entries[clean(newname)] = &memEntry{mode: os.ModeSymlink, symlinkTarget: oldname}
+	return nil
+}
+
+func (f *MemFilesystem) Remove(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := clean(name)
+	if _, ok := f.entries[key]; !ok {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+	delete(f.entries, key)
+	return nil
+}
+
+// RemoveAll removes name and, if it's a directory, every entry under it.
+func (f *MemFilesystem) RemoveAll(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := clean(name)
+	prefix := key + "/"
+	for childPath := range f.entries {
+		if childPath == key || strings.HasPrefix(childPath, prefix) {
+			delete(f.entries, childPath)
+		}
+	}
+	return nil
+}
+
+type memFileInfo struct {
+	name  string
+	entry *memEntry
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return 0 }
+func (i memFileInfo) Mode() os.FileMode  { return i.entry.mode }
+func (i memFileInfo) ModTime() time.Time { return time.Time{} }
+func (i memFileInfo) IsDir() bool        { return i.entry.isDir }
+func (i memFileInfo) Sys() interface{}   { return nil }
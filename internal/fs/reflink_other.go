@@ -0,0 +1,15 @@
+//go:build !linux
+
+package fs
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// reflinkFile always fails outside Linux; reflinkTree falls back to a
+// streamed copy when it does.
+func reflinkFile(src, dst string, mode os.FileMode) error {
+	return fmt.Errorf("fs: reflink is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,76 @@
+package fs
+
+import (
+	"errors"
+	"os"
+	"path"
+)
+
+// SkipDir is returned by a WalkFunc to indicate that the directory named in
+// the call should not be descended into. It mirrors filepath.SkipDir.
+var SkipDir = errors.New("fs: skip this directory")
+
+// WalkFunc is called once for every entry Walk visits. name is relative to
+// the Filesystem's root. Returning SkipDir when info is a directory prunes
+// its subtree without aborting the walk; any other non-nil error aborts it.
+type WalkFunc func(name string, info os.FileInfo, err error) error
+
+// WalkOptions tune how Walk descends into the tree.
+type WalkOptions struct {
+	// MaxDepth limits how many directory levels Walk descends below root.
+	// 0 means unlimited.
+	MaxDepth int
+	// FollowSymlinks makes Walk descend into directories reached through a
+	// symlink. Off by default, since a cyclic tree of symlinks would make
+	// Walk recurse forever.
+	FollowSymlinks bool
+}
+
+// Walk walks the tree rooted at name (relative to fsys's root) depth-first,
+// calling fn once for every file and directory it visits, root included.
+func Walk(fsys Filesystem, name string, opts WalkOptions, fn WalkFunc) error {
+	info, err := fsys.Lstat(name)
+	if err != nil {
+		return fn(name, nil, err)
+	}
+	return walk(fsys, name, info, 0, opts, fn)
+}
+
+func walk(fsys Filesystem, name string, info os.FileInfo, depth int, opts WalkOptions, fn WalkFunc) error {
+	if err := fn(name, info, nil); err != nil {
+		if err == SkipDir {
+			return nil
+		}
+		return err
+	}
+
+	dirInfo := info
+	if info.Mode()&os.ModeSymlink != 0 {
+		if !opts.FollowSymlinks {
+			return nil
+		}
+		resolved, err := fsys.Stat(name)
+		if err != nil {
+			// Broken symlink target: nothing to descend into.
+			return nil
+		}
+		dirInfo = resolved
+	}
+	if !dirInfo.IsDir() {
+		return nil
+	}
+	if opts.MaxDepth > 0 && depth >= opts.MaxDepth {
+		return nil
+	}
+
+	entries, err := fsys.ReadDir(name)
+	if err != nil {
+		return fn(name, info, err)
+	}
+	for _, entry := range entries {
+		if err := walk(fsys, path.Join(name, entry.Name()), entry, depth+1, opts, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package fs
+
+import (
+	"os"
+	"testing"
+)
+
+func TestWalkPrunesSkipDir(t *testing.T) {
+	fsys := NewMemFilesystem("root")
+	for _, dir := range []string{"a", "a/skipme", "a/skipme/nested", "a/keepme"} {
+		if err := fsys.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("MkdirAll(%q): %v", dir, err)
+		}
+	}
+
+	var visited []string
+	err := Walk(fsys, ".", WalkOptions{}, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		visited = append(visited, name)
+		if name == "a/skipme" {
+			return SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+
+	want := []string{".", "a", "a/keepme", "a/skipme"}
+	assertVisited(t, visited, want)
+}
+
+func TestWalkMaxDepth(t *testing.T) {
+	fsys := NewMemFilesystem("root")
+	for _, dir := range []string{"a", "a/b", "a/b/c"} {
+		if err := fsys.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("MkdirAll(%q): %v", dir, err)
+		}
+	}
+
+	var visited []string
+	err := Walk(fsys, ".", WalkOptions{MaxDepth: 1}, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		visited = append(visited, name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+
+	want := []string{".", "a"}
+	assertVisited(t, visited, want)
+}
+
+func TestWalkFollowSymlinks(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(root+"/real/nested", 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.Symlink(root+"/real", root+"/link"); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+	fsys := NewBasicFilesystem(root)
+
+	var visited []string
+	walkFn := func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		visited = append(visited, name)
+		return nil
+	}
+
+	visited = nil
+	if err := Walk(fsys, ".", WalkOptions{}, walkFn); err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	assertVisited(t, visited, []string{".", "link", "real", "real/nested"})
+
+	visited = nil
+	if err := Walk(fsys, ".", WalkOptions{FollowSymlinks: true}, walkFn); err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	assertVisited(t, visited, []string{".", "link", "link/nested", "real", "real/nested"})
+}
+
+func assertVisited(t *testing.T, got, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("visited %v, want %v", got, want)
+	}
+	gotSet := map[string]bool{}
+	for _, name := range got {
+		gotSet[name] = true
+	}
+	for _, name := range want {
+		if !gotSet[name] {
+			t.Errorf("visited %v missing %q", got, name)
+		}
+	}
+}
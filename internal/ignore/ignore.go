@@ -0,0 +1,82 @@
+// Package ignore implements doublestar-glob ignore matching, similar in
+// spirit to a .gitignore or syncthing .stignore file, shared by the walker
+// and watch mode.
+package ignore
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+type pattern struct {
+	glob   string
+	negate bool
+}
+
+// Matcher holds an ordered list of glob patterns. Later patterns take
+// precedence over earlier ones, and a pattern prefixed with "!" negates any
+// earlier match, the same way .gitignore/.stignore rules compose.
+type Matcher struct {
+	patterns []pattern
+}
+
+// New builds a Matcher from patterns, in the format accepted by Add.
+func New(patterns ...string) *Matcher {
+	m := &Matcher{}
+	m.Add(patterns...)
+	return m
+}
+
+// Add appends patterns to the matcher. Blank entries and ones starting with
+// "#" are ignored so callers can pass a file's lines through directly.
+func (m *Matcher) Add(patterns ...string) {
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" || strings.HasPrefix(p, "#") {
+			continue
+		}
+		negate := strings.HasPrefix(p, "!")
+		if negate {
+			p = p[1:]
+		}
+		m.patterns = append(m.patterns, pattern{glob: p, negate: negate})
+	}
+}
+
+// ShouldIgnore reports whether name matches the matcher's patterns. name is
+// tried both as given (so patterns like "**/*.tmp" can match a full relative
+// path) and as its final path element (so a bare pattern like "[BigShaq]*"
+// matches regardless of where the entry sits in the tree).
+func (m *Matcher) ShouldIgnore(name string) bool {
+	base := name
+	if idx := strings.LastIndex(name, "/"); idx != -1 {
+		base = name[idx+1:]
+	}
+
+	ignored := false
+	for _, p := range m.patterns {
+		matched, _ := doublestar.Match(p.glob, name)
+		if !matched {
+			matched, _ = doublestar.Match(p.glob, base)
+		}
+		if matched {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// ReadPatterns reads a .treeifyignore-style file, one glob per line, and
+// returns every line as-is, blank and "#" comment lines included. Skipping
+// those lines and handling a leading "!" negation is left to Add.
+func ReadPatterns(r io.Reader) ([]string, error) {
+	var patterns []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		patterns = append(patterns, scanner.Text())
+	}
+	return patterns, scanner.Err()
+}
@@ -0,0 +1,61 @@
+package ignore
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatcherShouldIgnore(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		want     bool
+	}{
+		{"no patterns", nil, "foo", false},
+		{"basename glob", []string{"Fire*"}, "music/Fire in the booth", true},
+		{"full path glob", []string{"**/*.tmp"}, "a/b/c.tmp", true},
+		{"full path glob no match", []string{"**/*.tmp"}, "a/b/c.mp3", false},
+		{"escaped brackets match literal folder", []string{`\[Temp Artist\]*`}, "[Temp Artist] skip me", true},
+		{"unescaped brackets are a character class", []string{"[Temp Artist]*"}, "[Temp Artist] skip me", false},
+		{"later pattern wins", []string{"*.tmp", "!keep.tmp"}, "keep.tmp", false},
+		{"negation only undoes an earlier match", []string{"!keep.tmp"}, "keep.tmp", false},
+		{"blank and comment lines ignored", []string{"", "# comment", "*.tmp"}, "a.tmp", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(tt.patterns...)
+			if got := m.ShouldIgnore(tt.path); got != tt.want {
+				t.Errorf("ShouldIgnore(%q) with patterns %v = %v, want %v", tt.path, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatcherAddTrimsAndSkips(t *testing.T) {
+	m := New()
+	m.Add("  *.tmp  ", "", "#ignored", "!kept.tmp")
+	if !m.ShouldIgnore("a.tmp") {
+		t.Error("expected *.tmp to be added and match a.tmp")
+	}
+	if m.ShouldIgnore("kept.tmp") {
+		t.Error("expected negation of kept.tmp to take effect")
+	}
+}
+
+func TestReadPatterns(t *testing.T) {
+	r := strings.NewReader("*.tmp\n\n# comment\n!kept.tmp\n")
+	patterns, err := ReadPatterns(r)
+	if err != nil {
+		t.Fatalf("ReadPatterns: %v", err)
+	}
+	want := []string{"*.tmp", "", "# comment", "!kept.tmp"}
+	if len(patterns) != len(want) {
+		t.Fatalf("ReadPatterns returned %v, want %v", patterns, want)
+	}
+	for i := range want {
+		if patterns[i] != want[i] {
+			t.Errorf("patterns[%d] = %q, want %q", i, patterns[i], want[i])
+		}
+	}
+}
@@ -1,126 +1,79 @@
 package main
 
 import (
-	"io/ioutil"
+	"fmt"
 	"log"
 	"os"
-	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
-	"sync/atomic"
-	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/Danlock/dir-treeify/internal/fs"
+	"github.com/Danlock/dir-treeify/internal/ignore"
 )
 
 type Flags struct {
 	RegexString           string
 	Regex                 *regexp.Regexp
-	IgnoredParents        []string
 	IgnoredParentsString  string
-	IgnoredChildren       []string
 	IgnoredChildrenString string
+	IgnoreParents         *ignore.Matcher
+	IgnoreChildren        *ignore.Matcher
+	// PathIgnore holds patterns loaded from .treeifyignore, matched against
+	// the raw folder name encountered while walking inputDir.
+	PathIgnore     *ignore.Matcher
+	MaxDepth       int
+	FollowSymlinks bool
+	Debounce       string
+	Concurrency    int
+	ReportFormat   string
+	LinkModeString string
+	LinkMode       fs.LinkMode
 }
 
-func consolidateFolders(flags Flags, inDirName, outDirName string) (err error) {
-	inDirName = filepath.Clean(inDirName)
-	outDirName = filepath.Clean(outDirName)
-	files, err := ioutil.ReadDir(inDirName)
-	if err != nil {
-		return
-	}
-
-	var ignored, errors, successes uint64
-	for _, f := range files {
-		go func(f os.FileInfo) {
-			fName := strings.TrimSpace(f.Name())
-			if !strings.ContainsAny(fName, "[ & ]") {
-				atomic.AddUint64(&errors, 1)
-				log.Printf("invalid dir named %s! skipping...", fName)
-				return
-			}
-			folderNames := flags.Regex.FindStringSubmatch(fName)
-			if folderNames == nil {
-				atomic.AddUint64(&errors, 1)
-				log.Printf("Could not parse folder %s!", fName)
-				return
-			}
-			var parentName, childName string
-			for i, v := range flags.Regex.SubexpNames() {
-				if v == "parent" {
-					parentName = strings.ToLower(folderNames[i])
-				} else if v == "child" {
-					childName = strings.TrimSpace(folderNames[i])
-				}
-			}
+var validLinkModes = []fs.LinkMode{fs.LinkModeSymlink, fs.LinkModeHardlink, fs.LinkModeReflink, fs.LinkModeCopy}
 
-			for _, v := range flags.IgnoredParents {
-				if strings.Contains(parentName, v) && v != "" {
-					atomic.AddUint64(&ignored, 1)
-					return
-				}
-			}
+// setupFlags parses flags.RegexString/IgnoredParentsString/IgnoredChildrenString/LinkModeString
+// and opens inFs/outFs, ready for consolidateFolders or watch.
+func setupFlags(flags *Flags, args []string) (inFs, outFs fs.Filesystem, err error) {
+	reg := regexp.MustCompile(flags.RegexString)
+	if reg.NumSubexp() != 2 {
+		log.Fatalln("Regex missing parameter groups!")
+	}
+	flags.Regex = reg
 
-			for _, v := range flags.IgnoredChildren {
-				if strings.Contains(childName, v) && v != "" {
-					atomic.AddUint64(&ignored, 1)
-					return
-				}
-			}
+	flags.IgnoreChildren = ignore.New(strings.Split(flags.IgnoredChildrenString, " ")...)
+	flags.IgnoreParents = ignore.New(strings.Split(flags.IgnoredParentsString, " ")...)
 
-			src, err := filepath.Abs(filepath.Join(inDirName, f.Name()))
-			if err != nil {
-				atomic.AddUint64(&errors, 1)
-				log.Printf("Could not parse folder %s! %s", fName, err)
-				return
-			}
-			destParent, err := filepath.Abs(filepath.Join(outDirName, parentName))
-			if err != nil {
-				atomic.AddUint64(&errors, 1)
-				log.Printf("Could not parse folder %s! %s", parentName, err)
-				return
-			}
-			dest, err := filepath.Abs(filepath.Join(outDirName, parentName, childName))
-			if err != nil {
-				atomic.AddUint64(&errors, 1)
-				log.Printf("Could not parse folder %s! %s", childName, err)
-				return
-			}
+	flags.LinkMode = fs.LinkMode(flags.LinkModeString)
+	valid := false
+	for _, m := range validLinkModes {
+		valid = valid || flags.LinkMode == m
+	}
+	if !valid {
+		return nil, nil, fmt.Errorf("invalid --link-mode %q, want one of %v", flags.LinkModeString, validLinkModes)
+	}
 
-			if err = os.MkdirAll(destParent, f.Mode()); err != nil {
-				atomic.AddUint64(&errors, 1)
-				log.Printf("Could not create destination folder %s! %s", destParent, err)
-				return
-			}
+	if flags.Concurrency < 1 {
+		return nil, nil, fmt.Errorf("invalid --concurrency %d, want a value >= 1", flags.Concurrency)
+	}
 
-			destInfo, err := os.Lstat(dest)
-			if err != nil && !os.IsNotExist(err) {
-				atomic.AddUint64(&errors, 1)
-				log.Printf("Destination not created! %s", err)
-				return
-			}
-			//If a symlink is already there, just delete it
-			if destInfo != nil && destInfo.Mode()&os.ModeSymlink != 0 {
-				if err := os.Remove(dest); err != nil {
-					atomic.AddUint64(&errors, 1)
-					log.Printf("Failed to create symlink! %s", err)
-					return
-				}
-			}
+	switch flags.ReportFormat {
+	case "json", "text", "":
+	default:
+		return nil, nil, fmt.Errorf("invalid --report %q, want json or text", flags.ReportFormat)
+	}
 
-			if err := os.Symlink(src, dest); err != nil {
-				atomic.AddUint64(&errors, 1)
-				log.Printf("\nError symlinking dir %s to %s!\nError:%s", src, dest, err)
-				return
-			}
-			atomic.AddUint64(&successes, 1)
-		}(f)
+	if inFs, err = fs.New(args[0]); err != nil {
+		return
 	}
-	//wait for goroutines to finish
-	for atomic.LoadUint64(&ignored)+atomic.LoadUint64(&errors)+atomic.LoadUint64(&successes) < uint64(len(files)) {
-		time.Sleep(10 * time.Millisecond)
+	if outFs, err = fs.New(args[1]); err != nil {
+		return
 	}
-	log.Printf("Finished linking %d folders! Ignored: %d Errors: %d ", successes, ignored, errors)
+
+	flags.PathIgnore, err = loadPathIgnore(inFs)
 	return
 }
 
@@ -130,32 +83,56 @@ func main() {
 		Use:   "dir-tree inputDir outputDir",
 		Short: "Restructure a folders subfolders via a regex pattern in their names.",
 		Long: `
-		If you had an inputDir that looks like so... 
+		If you had an inputDir that looks like so...
 			Music -> [Big Shaq] Mans not Hot
                               -> [Big Shaq] Fire in the booth
 		The outputDir could look like...
 			MusicByAuthor -> big shaq -> Mans not Hot
                                                   -> Fire in the booth
+
+		inputDir and outputDir accept plain paths (treated as file://) or
+		URIs such as mem://name for the in-memory backend.
+
+		Folders are matched recursively: a directory is walked all the way
+		down until one matches the regex, at which point that directory's
+		subtree is linked as a whole and not descended into further.
+
+		--ignore-parents/--ignore-children accept doublestar glob patterns
+		(` + "`**/*.tmp`" + `, ` + "`[BigShaq]*`" + `, with ` + "`!`" + ` negation), space
+		delimited. A ` + treeifyIgnoreFile + ` file in inputDir, one pattern
+		per line, adds further patterns matched against raw folder names.
+
+		--link-mode picks how a matched folder is materialized under
+		outputDir: symlink (default), hardlink, reflink (falls back to copy
+		where the filesystem lacks CoW clone support), or copy.
+
+		See "dir-tree watch --help" to keep outputDir in sync as inputDir changes.
 		`,
 		Args: cobra.ExactArgs(2),
 		Run: func(cmd *cobra.Command, args []string) {
-			reg := regexp.MustCompile(flags.RegexString)
-			if reg.NumSubexp() != 2 {
-				log.Fatalln("Regex missing parameter groups!")
+			inFs, outFs, err := setupFlags(&flags, args)
+			if err != nil {
+				log.Fatalf("Error:%s", err)
 			}
-			flags.Regex = reg
-
-			flags.IgnoredChildren = strings.Split(flags.IgnoredChildrenString, " ")
-			flags.IgnoredParents = strings.Split(flags.IgnoredParentsString, " ")
-
-			if err := consolidateFolders(flags, args[0], args[1]); err != nil {
+			results, err := consolidateFolders(flags, inFs, outFs)
+			if err != nil {
+				log.Fatalf("Error:%s", err)
+			}
+			if err := writeReport(os.Stdout, flags.ReportFormat, results); err != nil {
 				log.Fatalf("Error:%s", err)
 			}
 		},
 	}
 	rootCLI.PersistentFlags().StringVarP(&flags.RegexString, "regex", "r", `\[(?P<parent>.+?)\](?P<child>.+)`, "Regex for creating tree via 2 named capture groups called parent and child.")
-	rootCLI.PersistentFlags().StringVar(&flags.IgnoredParentsString, "ignore-parents", "", "Skips making any symlink parent that contains this string. Space delimited")
-	rootCLI.PersistentFlags().StringVar(&flags.IgnoredChildrenString, "ignore-children", "", "Skips making any symlink parent that contains this string. Space delimited")
+	rootCLI.PersistentFlags().StringVar(&flags.IgnoredParentsString, "ignore-parents", "", "Skips making any link whose parent matches one of these doublestar glob patterns. Space delimited")
+	rootCLI.PersistentFlags().StringVar(&flags.IgnoredChildrenString, "ignore-children", "", "Skips making any link whose child matches one of these doublestar glob patterns. Space delimited")
+	rootCLI.PersistentFlags().IntVar(&flags.MaxDepth, "max-depth", 0, "Limits how many directory levels are walked below inputDir. 0 means unlimited.")
+	rootCLI.PersistentFlags().BoolVar(&flags.FollowSymlinks, "follow-symlinks", false, "Follow symlinked directories while walking inputDir. Off by default to avoid looping on cyclic trees.")
+	rootCLI.PersistentFlags().IntVar(&flags.Concurrency, "concurrency", runtime.NumCPU(), "Max number of folders to link at once.")
+	rootCLI.PersistentFlags().StringVar(&flags.ReportFormat, "report", "text", "Output format for the summary report: json or text.")
+	rootCLI.PersistentFlags().StringVar(&flags.LinkModeString, "link-mode", string(fs.LinkModeSymlink), "How to materialize a matched folder under outputDir: symlink, hardlink, reflink or copy.")
+
+	rootCLI.AddCommand(newWatchCmd(&flags))
 
 	if err := rootCLI.Execute(); err != nil {
 		log.Fatalf("Failure because %s!", err)
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Result records the outcome of consolidating a single matched folder, for
+// the --report summary.
+type Result struct {
+	Src    string
+	Dest   string
+	Status string
+	Err    error
+}
+
+// resultJSON mirrors Result but renders Err as a plain string, since error
+// values don't marshal usefully on their own.
+type resultJSON struct {
+	Src    string `json:"src"`
+	Dest   string `json:"dest"`
+	Status string `json:"status"`
+	Err    string `json:"err,omitempty"`
+}
+
+func (r Result) MarshalJSON() ([]byte, error) {
+	j := resultJSON{Src: r.Src, Dest: r.Dest, Status: r.Status}
+	if r.Err != nil {
+		j.Err = r.Err.Error()
+	}
+	return json.Marshal(j)
+}
+
+// writeReport renders results to w as either a one-line-per-error "text"
+// summary or a "json" array, mirroring what consolidateFolders used to just
+// log.Printf.
+func writeReport(w io.Writer, format string, results []Result) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	case "text", "":
+		var linked, ignored, errored int
+		for _, r := range results {
+			switch r.Status {
+			case "linked":
+				linked++
+			case "ignored":
+				ignored++
+			case "error":
+				errored++
+				fmt.Fprintf(w, "Error linking %s to %s: %s\n", r.Src, r.Dest, r.Err)
+			}
+		}
+		fmt.Fprintf(w, "Finished linking %d folders! Ignored: %d Errors: %d\n", linked, ignored, errored)
+		return nil
+	default:
+		return fmt.Errorf("unknown --report format %q, want json or text", format)
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+
+	"github.com/Danlock/dir-treeify/internal/fs"
+)
+
+func newWatchCmd(flags *Flags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch inputDir outputDir",
+		Short: "Consolidate inputDir into outputDir, then keep outputDir in sync as inputDir changes.",
+		Long: `
+		Performs the same consolidation as the root command, then subscribes
+		to filesystem events on inputDir: directories created, renamed or
+		removed have their link under outputDir added, updated or removed
+		to match. Bursts of events are coalesced with --debounce before being
+		acted on, the same ignore patterns as the root command apply, and
+		Ctrl-C shuts the watch down cleanly.
+
+		Only the file:// backend can be watched.
+		`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			inFs, outFs, err := setupFlags(flags, args)
+			if err != nil {
+				log.Fatalf("Error:%s", err)
+			}
+			if inFs.Type() != fs.FilesystemTypeBasic {
+				log.Fatalf("watch only supports the file:// backend, got %s", inFs.URI())
+			}
+
+			debounce, err := time.ParseDuration(flags.Debounce)
+			if err != nil {
+				log.Fatalf("Invalid --debounce %q: %s", flags.Debounce, err)
+			}
+
+			results, err := consolidateFolders(*flags, inFs, outFs)
+			if err != nil {
+				log.Fatalf("Error:%s", err)
+			}
+			if err := writeReport(os.Stdout, flags.ReportFormat, results); err != nil {
+				log.Fatalf("Error:%s", err)
+			}
+			if err := watch(*flags, inFs, outFs, debounce); err != nil {
+				log.Fatalf("Error:%s", err)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&flags.Debounce, "debounce", "2s", "Coalesces bursts of filesystem events on the same directory within this interval before acting on them.")
+	return cmd
+}
+
+// watch subscribes to filesystem events under inFs and keeps outFs's
+// links in sync until ctx is canceled (SIGINT).
+func watch(flags Flags, inFs, outFs fs.Filesystem, debounce time.Duration) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := addWatches(flags, inFs, outFs, watcher); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	debouncer := newEventDebouncer(debounce)
+	defer debouncer.stop()
+
+	log.Printf("Watching %s for changes, writing to %s...", inFs.URI(), outFs.URI())
+	for {
+		select {
+		case <-ctx.Done():
+			log.Print("Shutting down watch...")
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			handleWatchEvent(flags, inFs, outFs, watcher, debouncer, event)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("watch error: %s", err)
+		}
+	}
+}
+
+// addWatches registers a watch on inFs's root and every directory that the
+// one-shot walk would descend into, i.e. everything except matched
+// subtrees, ignored paths, and outFs's own directory.
+func addWatches(flags Flags, inFs, outFs fs.Filesystem, watcher *fsnotify.Watcher) error {
+	opts := fs.WalkOptions{MaxDepth: flags.MaxDepth, FollowSymlinks: flags.FollowSymlinks}
+	return fs.Walk(inFs, ".", opts, func(name string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if name != "." {
+			if isOutputDir(inFs, outFs, name) || flags.PathIgnore.ShouldIgnore(name) {
+				return fs.SkipDir
+			}
+			if isMatch(flags, info.Name()) {
+				// Linked as a whole; nothing inside needs its own watch.
+				return fs.SkipDir
+			}
+		}
+		return watcher.Add(realPath(inFs, name))
+	})
+}
+
+// isMatch reports whether fName passes the same quick filter and regex
+// match findMatches uses to decide a directory is a consolidation target.
+func isMatch(flags Flags, fName string) bool {
+	fName = strings.TrimSpace(fName)
+	return strings.ContainsAny(fName, "[ & ]") && flags.Regex.FindStringSubmatch(fName) != nil
+}
+
+// relName turns an absolute path reported by fsnotify into a name relative
+// to inFs's root, as processEntry/removeEntry expect.
+func relName(inFs fs.Filesystem, absPath string) string {
+	rel, err := filepath.Rel(fs.RootPath(inFs), absPath)
+	if err != nil {
+		return absPath
+	}
+	return rel
+}
+
+func handleWatchEvent(flags Flags, inFs, outFs fs.Filesystem, watcher *fsnotify.Watcher, debouncer *eventDebouncer, event fsnotify.Event) {
+	name := relName(inFs, event.Name)
+	if flags.PathIgnore.ShouldIgnore(name) {
+		return
+	}
+
+	switch {
+	case event.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+		debouncer.schedule(name, func() {
+			if err := removeEntry(flags, outFs, name); err != nil {
+				log.Print(err)
+				return
+			}
+			log.Printf("Removed symlink for %s", name)
+		})
+
+	case event.Op&fsnotify.Create != 0:
+		info, err := inFs.Lstat(name)
+		if err != nil || !info.IsDir() {
+			return
+		}
+		if !isMatch(flags, info.Name()) {
+			// A new container directory: watch it too, so matches created
+			// inside it are picked up.
+			if err := watcher.Add(event.Name); err != nil {
+				log.Printf("could not watch new directory %s: %s", name, err)
+			}
+			return
+		}
+		debouncer.schedule(name, func() {
+			dest, ignored, err := processEntry(flags, inFs, outFs, name)
+			switch {
+			case ignored:
+				log.Printf("Ignored %s", name)
+			case err != nil:
+				log.Print(err)
+			default:
+				log.Printf("Linked %s -> %s", name, dest)
+			}
+		})
+	}
+}
+
+// eventDebouncer coalesces bursts of events on the same key, running fn only
+// once the key has been quiet for interval.
+type eventDebouncer struct {
+	interval time.Duration
+
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+}
+
+func newEventDebouncer(interval time.Duration) *eventDebouncer {
+	return &eventDebouncer{interval: interval, timers: map[string]*time.Timer{}}
+}
+
+func (d *eventDebouncer) schedule(key string, fn func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if t, ok := d.timers[key]; ok {
+		t.Stop()
+	}
+	d.timers[key] = time.AfterFunc(d.interval, func() {
+		d.mu.Lock()
+		delete(d.timers, key)
+		d.mu.Unlock()
+		fn()
+	})
+}
+
+func (d *eventDebouncer) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, t := range d.timers {
+		t.Stop()
+	}
+}